@@ -0,0 +1,262 @@
+package compress
+
+/*
+gin-compress Copyright (C) 2022 Aurora McGinnis
+Modifications Copyright (C) 2025 Rubén del Campo
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+
+import (
+	"io"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Algorithm abstracts over a single compression codec: it knows its
+// Content-Encoding token and how to wrap a writer/reader with a
+// (de)compressor. Implement it to plug a custom codec (e.g. Snappy, LZ4)
+// into Compress/Decompress via RegisterAlgorithm or WithAlgorithm.
+type Algorithm interface {
+	// Encoding is the Content-Encoding token this algorithm is registered
+	// under, e.g. "gzip".
+	Encoding() string
+	// NewWriter returns a compressor that writes compressed output to w.
+	NewWriter(w io.Writer) io.WriteCloser
+	// NewReader returns a decompressor reading encoded data from r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// LeveledAlgorithm is implemented by algorithms with an adjustable
+// compression level. WithCompressLevel uses it when present and is a no-op
+// for algorithms that don't support it.
+type LeveledAlgorithm interface {
+	Algorithm
+	// WithLevel returns a copy of this algorithm configured at level.
+	WithLevel(level int) Algorithm
+}
+
+type gzipAlgorithm struct {
+	level int
+	pool  *sync.Pool
+}
+
+func newGzipAlgorithm(level int) gzipAlgorithm {
+	return gzipAlgorithm{
+		level: level,
+		pool: &sync.Pool{
+			New: func() any {
+				zw, _ := gzip.NewWriterLevel(io.Discard, level)
+				return zw
+			},
+		},
+	}
+}
+
+func (a gzipAlgorithm) Encoding() string { return "gzip" }
+
+func (a gzipAlgorithm) NewWriter(w io.Writer) io.WriteCloser {
+	zw := a.pool.Get().(*gzip.Writer)
+	zw.Reset(w)
+	return &pooledWriteCloser{WriteCloser: zw, flush: zw.Flush, put: func() { a.pool.Put(zw) }}
+}
+
+func (a gzipAlgorithm) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+func (a gzipAlgorithm) WithLevel(level int) Algorithm {
+	return newGzipAlgorithm(level)
+}
+
+type deflateAlgorithm struct {
+	level int
+	pool  *sync.Pool
+}
+
+func newDeflateAlgorithm(level int) deflateAlgorithm {
+	return deflateAlgorithm{
+		level: level,
+		pool: &sync.Pool{
+			New: func() any {
+				zw, _ := zlib.NewWriterLevel(io.Discard, level)
+				return zw
+			},
+		},
+	}
+}
+
+func (a deflateAlgorithm) Encoding() string { return "deflate" }
+
+func (a deflateAlgorithm) NewWriter(w io.Writer) io.WriteCloser {
+	zw := a.pool.Get().(*zlib.Writer)
+	zw.Reset(w)
+	return &pooledWriteCloser{WriteCloser: zw, flush: zw.Flush, put: func() { a.pool.Put(zw) }}
+}
+
+func (a deflateAlgorithm) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return zlib.NewReader(r)
+}
+
+func (a deflateAlgorithm) WithLevel(level int) Algorithm {
+	return newDeflateAlgorithm(level)
+}
+
+type brotliAlgorithm struct {
+	level int
+	pool  *sync.Pool
+}
+
+func newBrotliAlgorithm(level int) brotliAlgorithm {
+	return brotliAlgorithm{
+		level: level,
+		pool: &sync.Pool{
+			New: func() any {
+				return brotli.NewWriterLevel(io.Discard, level)
+			},
+		},
+	}
+}
+
+func (a brotliAlgorithm) Encoding() string { return "br" }
+
+func (a brotliAlgorithm) NewWriter(w io.Writer) io.WriteCloser {
+	bw := a.pool.Get().(*brotli.Writer)
+	bw.Reset(w)
+	return &pooledWriteCloser{WriteCloser: bw, flush: bw.Flush, put: func() { a.pool.Put(bw) }}
+}
+
+func (a brotliAlgorithm) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(brotli.NewReader(r)), nil
+}
+
+func (a brotliAlgorithm) WithLevel(level int) Algorithm {
+	return newBrotliAlgorithm(level)
+}
+
+type zstdAlgorithm struct {
+	level zstd.EncoderLevel
+	dict  []byte
+	pool  *sync.Pool
+}
+
+func newZstdAlgorithm(level zstd.EncoderLevel, dict []byte) zstdAlgorithm {
+	// Validate the dictionary eagerly: an invalid dictionary would
+	// otherwise only surface as a nil *zstd.Encoder the first time a
+	// pooled writer is requested for an actual response.
+	if zw, err := zstd.NewWriter(io.Discard, zstdEncoderOptions(level, dict)...); err != nil {
+		dict = nil
+	} else {
+		zw.Close()
+	}
+
+	return zstdAlgorithm{
+		level: level,
+		dict:  dict,
+		pool: &sync.Pool{
+			New: func() any {
+				zw, _ := zstd.NewWriter(io.Discard, zstdEncoderOptions(level, dict)...)
+				return zw
+			},
+		},
+	}
+}
+
+func zstdEncoderOptions(level zstd.EncoderLevel, dict []byte) []zstd.EOption {
+	opts := []zstd.EOption{zstd.WithEncoderLevel(level)}
+	if len(dict) > 0 {
+		opts = append(opts, zstd.WithEncoderDict(dict))
+	}
+	return opts
+}
+
+func (a zstdAlgorithm) Encoding() string { return "zstd" }
+
+func (a zstdAlgorithm) NewWriter(w io.Writer) io.WriteCloser {
+	zw := a.pool.Get().(*zstd.Encoder)
+	zw.Reset(w)
+	return &pooledWriteCloser{WriteCloser: zw, flush: zw.Flush, put: func() { a.pool.Put(zw) }}
+}
+
+func (a zstdAlgorithm) NewReader(r io.Reader) (io.ReadCloser, error) {
+	opts := []zstd.DOption(nil)
+	if len(a.dict) > 0 {
+		opts = append(opts, zstd.WithDecoderDicts(a.dict))
+	}
+	zr, err := zstd.NewReader(r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return zstdReadCloser{zr}, nil
+}
+
+func (a zstdAlgorithm) WithLevel(level int) Algorithm {
+	return newZstdAlgorithm(zstd.EncoderLevel(level), a.dict)
+}
+
+// WithDict returns a copy of this algorithm using dict for both encoding
+// and decoding, per zstd.WithEncoderDict/WithDecoderDicts.
+func (a zstdAlgorithm) WithDict(dict []byte) zstdAlgorithm {
+	return newZstdAlgorithm(a.level, dict)
+}
+
+// zstdReadCloser adapts *zstd.Decoder's Close (which returns nothing) to the
+// io.ReadCloser interface expected by Algorithm.NewReader.
+type zstdReadCloser struct {
+	*zstd.Decoder
+}
+
+func (z zstdReadCloser) Close() error {
+	z.Decoder.Close()
+	return nil
+}
+
+// pooledWriteCloser wraps a compressor borrowed from a sync.Pool. Close
+// finalizes the compressed stream as usual, then returns the compressor to
+// its pool so the next request reuses it instead of allocating a fresh one.
+type pooledWriteCloser struct {
+	io.WriteCloser
+	flush func() error
+	put   func()
+}
+
+func (p *pooledWriteCloser) Flush() error {
+	return p.flush()
+}
+
+func (p *pooledWriteCloser) Close() error {
+	err := p.WriteCloser.Close()
+	p.put()
+	return err
+}
+
+// defaultAlgorithms returns the built-in codecs registered by
+// newCompressOptions, each configured at its own default level with a
+// pooled writer.
+func defaultAlgorithms() map[string]Algorithm {
+	return map[string]Algorithm{
+		"gzip":    newGzipAlgorithm(gzip.DefaultCompression),
+		"deflate": newDeflateAlgorithm(zlib.DefaultCompression),
+		"br":      newBrotliAlgorithm(brotli.DefaultCompression),
+		"zstd":    newZstdAlgorithm(zstd.SpeedDefault, nil),
+	}
+}
+
+// defaultPriorities ranks encodings when the client's Accept-Encoding gives
+// several of them equal weight. Brotli generally yields the best
+// size/CPU tradeoff for HTTP responses, followed by zstd, which in turn
+// beats gzip and deflate on both ratio and speed at their default levels.
+func defaultPriorities() map[string]float64 {
+	return map[string]float64{
+		"br":      4,
+		"zstd":    3,
+		"gzip":    2,
+		"deflate": 1,
+	}
+}
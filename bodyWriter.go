@@ -10,8 +10,13 @@ file, You can obtain one at https://mozilla.org/MPL/2.0/.
 */
 
 import (
+	"bufio"
 	"bytes"
 	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,10 +28,35 @@ type respWriter struct {
 	gin.ResponseWriter
 	threshold  int
 	encoding   string
-	algo       algorithm
+	algo       Algorithm
 	buf        *bytes.Buffer
 	compressor io.WriteCloser
 
+	// request is the incoming request being served, used to decide whether
+	// compression should be bypassed (e.g. gRPC-over-HTTP/2).
+	request *http.Request
+	// contentTypes is the allowlist of response Content-Types eligible for
+	// compression, from WithCompressibleContentTypes.
+	contentTypes []string
+	// minContentLength is the smallest handler-declared Content-Length
+	// worth compressing, from WithMinContentLength.
+	minContentLength int
+	// flushMode controls what Flush does before the response is swapped,
+	// from WithFlushMode.
+	flushMode FlushMode
+	// bypassDecided is true once shouldBypass has been evaluated for this
+	// response. The decision is made on the first Write so that the handler
+	// has had a chance to set response headers.
+	bypassDecided bool
+	// bypassed is true if this response was decided to skip compression
+	// entirely, writing straight through to the wrapped ResponseWriter.
+	bypassed bool
+	// declaredContentLength is the Content-Length the handler set (if any)
+	// before WriteHeader cleared it, used by shouldBypass to honor
+	// minContentLength even though the real header is gone by the time
+	// Write runs.
+	declaredContentLength string
+
 	// bytesWritten is the size of the original data written to the response writer
 	bytesWritten int
 	// compressedBytesCount is the size of the compressed data written to the response writer (if compression was applied)
@@ -37,21 +67,26 @@ type respWriter struct {
 	trackingWriter *trackingResponseWriter
 }
 
-func newResponseWriter(c *gin.Context, swapSize int, encoding string, algo algorithm, metricsHandler MetricsHandler) *respWriter {
+func newResponseWriter(c *gin.Context, opts *compressOptions, encoding string, algo Algorithm) *respWriter {
 	// Create a tracking writer to count actual bytes written to the response
 	tracker := newTrackingResponseWriter(c, nil)
 
 	return &respWriter{
 		ResponseWriter: tracker,
-		threshold:      swapSize,
+		threshold:      opts.threshold,
 		encoding:       encoding,
 		algo:           algo,
 		buf:            bytes.NewBuffer(nil),
 		compressor:     nil,
 
+		request:          c.Request,
+		contentTypes:     opts.contentTypes,
+		minContentLength: opts.minContentLength,
+		flushMode:        opts.flushMode,
+
 		bytesWritten:         0,
 		compressedBytesCount: 0,
-		metricsHandler:       metricsHandler,
+		metricsHandler:       opts.metricsHandler,
 		trackingWriter:       tracker,
 	}
 }
@@ -61,16 +96,18 @@ func (rw *respWriter) WriteString(s string) (int, error) {
 }
 
 func (rw *respWriter) Write(b []byte) (int, error) {
+	rw.decideBypass()
+
+	if rw.bypassed {
+		n, err := rw.ResponseWriter.Write(b)
+		rw.bytesWritten += n
+		return n, err
+	}
+
 	rw.Header().Del("Content-Length")
 
 	if !rw.Swapped() && rw.buf.Len()+len(b) >= rw.threshold {
-		rw.ResponseWriter.Header().Set("Content-Encoding", rw.encoding)
-		rw.ResponseWriter.Header().Set("Vary", "Accept-Encoding")
-		rw.compressor = rw.algo.getWriter(rw.ResponseWriter)
-		if copied, err := io.Copy(rw.compressor, rw.buf); err != nil {
-			return int(copied), err
-		}
-		rw.buf = nil
+		rw.swap()
 	}
 
 	var w io.Writer
@@ -88,6 +125,104 @@ func (rw *respWriter) Write(b []byte) (int, error) {
 	}
 }
 
+// swap switches the response writer over to its compressor, flushing
+// whatever has been buffered so far through it.
+func (rw *respWriter) swap() error {
+	rw.ResponseWriter.Header().Set("Content-Encoding", rw.encoding)
+	rw.ResponseWriter.Header().Set("Vary", "Accept-Encoding")
+	rw.compressor = rw.algo.NewWriter(rw.ResponseWriter)
+	buffered := rw.buf
+	rw.buf = nil
+	_, err := io.Copy(rw.compressor, buffered)
+	return err
+}
+
+// shouldBypass reports whether this response should skip compression
+// entirely: the request declares a gRPC payload (compression would break
+// gRPC-over-HTTP/2 framing), the request or response already carries a
+// non-identity Content-Encoding (the body is pre-encoded; compressing it
+// again would corrupt it), the response's Content-Type isn't in the
+// compressible allowlist, or the handler pre-declared a Content-Length
+// below minContentLength.
+func (rw *respWriter) shouldBypass() bool {
+	if strings.HasPrefix(rw.request.Header.Get("Content-Type"), "application/grpc") {
+		return true
+	}
+
+	if enc := rw.request.Header.Get("Content-Encoding"); enc != "" && enc != "identity" {
+		return true
+	}
+
+	if enc := rw.Header().Get("Content-Encoding"); enc != "" && enc != "identity" {
+		return true
+	}
+
+	if !contentTypeAllowed(rw.contentTypes, rw.Header().Get("Content-Type")) {
+		return true
+	}
+
+	if rw.minContentLength > 0 {
+		declared := rw.declaredContentLength
+		if declared == "" {
+			declared = rw.Header().Get("Content-Length")
+		}
+		if cl, err := strconv.Atoi(declared); err == nil && cl < rw.minContentLength {
+			return true
+		}
+	}
+
+	return false
+}
+
+// decideBypass evaluates shouldBypass once per response, memoizing the
+// result so later calls from Write, WriteHeader and Flush agree on it.
+func (rw *respWriter) decideBypass() {
+	if !rw.bypassDecided {
+		rw.bypassDecided = true
+		rw.bypassed = rw.shouldBypass()
+	}
+}
+
+// Flush implements http.Flusher. If the response has already been swapped
+// to its compressor, the compressor is flushed first so buffered compressed
+// bytes reach the client, then the underlying ResponseWriter is flushed.
+// Otherwise, behavior is governed by FlushMode: FlushModeAlways forces an
+// immediate swap so the response keeps streaming compressed, FlushModeAuto
+// bypasses compression so the unbuffered bytes are flushed immediately, and
+// FlushModeNever leaves the buffer untouched.
+func (rw *respWriter) Flush() {
+	rw.decideBypass()
+
+	if !rw.bypassed && !rw.Swapped() {
+		switch rw.flushMode {
+		case FlushModeAlways:
+			rw.swap()
+		case FlushModeAuto:
+			rw.bypassed = true
+			// bytesWritten already counted these bytes when Write buffered
+			// them; don't add them again here.
+			_, _ = rw.ResponseWriter.Write(rw.buf.Bytes())
+			rw.buf.Reset()
+		case FlushModeNever:
+			// leave buffered bytes untouched
+		}
+	}
+
+	if rw.Swapped() {
+		if f, ok := rw.compressor.(interface{ Flush() error }); ok {
+			_ = f.Flush()
+		}
+	}
+
+	rw.ResponseWriter.Flush()
+}
+
+// Hijack implements http.Hijacker by delegating to the wrapped
+// ResponseWriter.
+func (rw *respWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return rw.ResponseWriter.Hijack()
+}
+
 func (rw *respWriter) Size() int {
 	return rw.bytesWritten
 }
@@ -158,6 +293,13 @@ func (rw *respWriter) Swapped() bool {
 }
 
 func (rw *respWriter) WriteHeader(code int) {
+	// Stash the handler-declared Content-Length before clearing it: the
+	// bypass decision in Write (made once the Content-Type is also known)
+	// needs it, but WriteHeader tends to fire before the body, and
+	// therefore the real Content-Type, is written.
+	if !rw.bypassDecided {
+		rw.declaredContentLength = rw.Header().Get("Content-Length")
+	}
 	rw.Header().Del("Content-Length")
 	rw.ResponseWriter.WriteHeader(code)
 }
@@ -0,0 +1,121 @@
+package compress
+
+/*
+gin-compress Copyright (C) 2022 Aurora McGinnis
+Modifications Copyright (C) 2025 Rubén del Campo
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// compressMiddleware holds the resolved options for a single Compress()
+// installation and produces the gin.HandlerFunc that negotiates and applies
+// compression for each request.
+type compressMiddleware struct {
+	opts *compressOptions
+}
+
+// newCompressMiddleware builds a compressMiddleware from already-applied
+// options.
+func newCompressMiddleware(opts *compressOptions) *compressMiddleware {
+	return &compressMiddleware{opts: opts}
+}
+
+// Handler is the gin.HandlerFunc installed by Compress(). It negotiates an
+// encoding from the request's Accept-Encoding header and, if one is found,
+// swaps in a respWriter that buffers and compresses the response.
+func (cm *compressMiddleware) Handler(c *gin.Context) {
+	if requestExcluded(cm.opts, c.Request.Host, c.Request.URL.Path) {
+		c.Next()
+		return
+	}
+
+	acceptEncoding, present := c.Request.Header["Accept-Encoding"]
+
+	var algo Algorithm
+	if present {
+		algo = cm.negotiate(strings.Join(acceptEncoding, ","))
+	} else if cm.opts.defaultEncoding != "" {
+		algo = cm.opts.algorithms[cm.opts.defaultEncoding]
+	}
+
+	if algo == nil {
+		c.Next()
+		return
+	}
+
+	rw := newResponseWriter(c, cm.opts, algo.Encoding(), algo)
+	c.Writer = rw
+	defer rw.Close()
+
+	c.Next()
+}
+
+// negotiate picks the best algorithm registered on this middleware for the
+// given Accept-Encoding header value, or nil if none are acceptable. Ties in
+// weight are broken by the algorithm's configured priority.
+func (cm *compressMiddleware) negotiate(acceptEncoding string) Algorithm {
+	if acceptEncoding == "" {
+		return nil
+	}
+
+	type candidate struct {
+		algo   Algorithm
+		weight float64
+	}
+
+	var best *candidate
+	bestPriority := -1.0
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, weight := parseEncodingToken(part)
+		if weight <= 0 {
+			continue
+		}
+
+		algo, ok := cm.opts.algorithms[name]
+		if !ok {
+			continue
+		}
+
+		priority := cm.opts.priorities[name]
+
+		if best == nil || weight > best.weight || (weight == best.weight && priority > bestPriority) {
+			best = &candidate{algo: algo, weight: weight}
+			bestPriority = priority
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+	return best.algo
+}
+
+// parseEncodingToken splits a single Accept-Encoding list item such as
+// " gzip;q=0.5" into its coding name and weight (defaulting to 1.0 when no
+// q-value is present or it fails to parse).
+func parseEncodingToken(part string) (string, float64) {
+	fields := strings.Split(part, ";")
+	name := strings.ToLower(strings.TrimSpace(fields[0]))
+
+	weight := 1.0
+	for _, param := range fields[1:] {
+		param = strings.TrimSpace(param)
+		if q, ok := strings.CutPrefix(param, "q="); ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				weight = parsed
+			}
+		}
+	}
+
+	return name, weight
+}
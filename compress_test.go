@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -50,6 +51,12 @@ func setupRouter(opts ...compress.CompressOption) *gin.Engine {
 	r.GET("/large", func(c *gin.Context) {
 		c.String(200, largeBody)
 	})
+	r.GET("/api/large", func(c *gin.Context) {
+		c.String(200, largeBody)
+	})
+	r.GET("/api/v1/large", func(c *gin.Context) {
+		c.String(200, largeBody)
+	})
 	r.POST("/echo", func(c *gin.Context) {
 		c.Header("X-Request-Content-Encoding", c.GetHeader("Content-Encoding"))
 
@@ -60,6 +67,27 @@ func setupRouter(opts ...compress.CompressOption) *gin.Engine {
 
 		c.Data(200, "text/plain", b.Bytes())
 	})
+	r.GET("/large-image", func(c *gin.Context) {
+		c.Data(200, "image/png", []byte(largeBody))
+	})
+	r.GET("/large-preencoded", func(c *gin.Context) {
+		c.Header("Content-Encoding", "gzip")
+		c.String(200, largeBody)
+	})
+	r.POST("/grpc", func(c *gin.Context) {
+		c.String(200, largeBody)
+	})
+	r.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/plain")
+		for i := 0; i < 3; i++ {
+			c.Writer.WriteString("chunk")
+			c.Writer.Flush()
+		}
+	})
+	r.GET("/declared-small", func(c *gin.Context) {
+		c.Header("Content-Length", strconv.Itoa(len(largeBody)))
+		c.String(200, largeBody)
+	})
 
 	return r
 }
@@ -115,6 +143,134 @@ func TestCompressNoopNoneAcceptable2(t *testing.T) {
 	assert.Equal(t, w.Body.String(), largeBody)
 }
 
+func TestCompressDefaultEncoding(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/large", nil)
+
+	r := setupRouter(compress.WithDefaultEncoding("gzip"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	checkCompress(t, w, "gzip")
+
+	gz, err := gzip.NewReader(w.Body)
+	assert.NoError(t, err)
+	defer gz.Close()
+
+	b, err := io.ReadAll(gz)
+	assert.NoError(t, err)
+	assert.Equal(t, largeBody, string(b))
+}
+
+func TestCompressDefaultEncodingNotUsedWhenHeaderPresent(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/large", nil)
+	req.Header.Set("Accept-Encoding", "doesnotexist")
+
+	r := setupRouter(compress.WithDefaultEncoding("gzip"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	checkNoop(t, w)
+	assert.Equal(t, w.Body.String(), largeBody)
+}
+
+func TestCompressIncludePaths(t *testing.T) {
+	r := setupRouter(compress.WithIncludePaths([]string{"/api/*"}))
+
+	included, _ := http.NewRequest("GET", "/api/large", nil)
+	included.Header.Add("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, included)
+	checkCompress(t, w, "gzip")
+
+	excluded, _ := http.NewRequest("GET", "/large", nil)
+	excluded.Header.Add("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, excluded)
+	checkNoop(t, w)
+	assert.Equal(t, w.Body.String(), largeBody)
+}
+
+func TestCompressExcludePaths(t *testing.T) {
+	r := setupRouter(compress.WithExcludePaths([]string{"/api/*"}))
+
+	req, _ := http.NewRequest("GET", "/api/large", nil)
+	req.Header.Add("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	checkNoop(t, w)
+	assert.Equal(t, w.Body.String(), largeBody)
+
+	req, _ = http.NewRequest("GET", "/large", nil)
+	req.Header.Add("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	checkCompress(t, w, "gzip")
+}
+
+func TestCompressExcludePathsTakesPrecedence(t *testing.T) {
+	r := setupRouter(
+		compress.WithIncludePaths([]string{"/api/*"}),
+		compress.WithExcludePaths([]string{"/api/large"}),
+	)
+
+	req, _ := http.NewRequest("GET", "/api/large", nil)
+	req.Header.Add("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	checkNoop(t, w)
+	assert.Equal(t, w.Body.String(), largeBody)
+}
+
+func TestCompressIncludePathsDoubleStarCrossesSegments(t *testing.T) {
+	r := setupRouter(compress.WithIncludePaths([]string{"/api/**"}))
+
+	nested, _ := http.NewRequest("GET", "/api/v1/large", nil)
+	nested.Header.Add("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, nested)
+	checkCompress(t, w, "gzip")
+
+	excluded, _ := http.NewRequest("GET", "/large", nil)
+	excluded.Header.Add("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, excluded)
+	checkNoop(t, w)
+	assert.Equal(t, w.Body.String(), largeBody)
+}
+
+func TestCompressExcludeHosts(t *testing.T) {
+	r := setupRouter(compress.WithExcludeHosts([]string{"internal.example.com"}))
+
+	req, _ := http.NewRequest("GET", "/large", nil)
+	req.Header.Add("Accept-Encoding", "gzip")
+	req.Host = "internal.example.com:8080"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	checkNoop(t, w)
+	assert.Equal(t, w.Body.String(), largeBody)
+
+	req, _ = http.NewRequest("GET", "/large", nil)
+	req.Header.Add("Accept-Encoding", "gzip")
+	req.Host = "public.example.com"
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	checkCompress(t, w, "gzip")
+}
+
+func TestCompressExcludeHostsCaseInsensitive(t *testing.T) {
+	r := setupRouter(compress.WithExcludeHosts([]string{"Internal.Example.com"}))
+
+	req, _ := http.NewRequest("GET", "/large", nil)
+	req.Header.Add("Accept-Encoding", "gzip")
+	req.Host = "INTERNAL.EXAMPLE.COM"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	checkNoop(t, w)
+	assert.Equal(t, w.Body.String(), largeBody)
+}
+
 func TestCompressGzip(t *testing.T) {
 	req, _ := http.NewRequest("GET", "/large", nil)
 	req.Header.Add("Accept-Encoding", "gzip")
@@ -219,6 +375,235 @@ func TestQ(t *testing.T) {
 	assert.Equal(t, b.String(), largeBody)
 }
 
+// TestCompressPriorityTieBreak locks in the default priority order used
+// when several encodings tie on q-value: br, then zstd (better ratio/speed
+// than gzip/deflate at default levels), then gzip, then deflate.
+func TestCompressPriorityTieBreak(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/large", nil)
+	req.Header.Add("Accept-Encoding", "gzip, zstd, deflate")
+	r := setupRouter()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	checkCompress(t, w, "zstd")
+}
+
+func setupDecompressRouter(opts ...compress.DecompressOption) *gin.Engine {
+	r := gin.Default()
+	r.Use(compress.Decompress(opts...))
+
+	r.POST("/echo", func(c *gin.Context) {
+		c.Header("X-Request-Content-Encoding", c.GetHeader("Content-Encoding"))
+
+		b := bytes.NewBuffer(nil)
+		if _, err := io.Copy(b, c.Request.Body); err != nil {
+			panic(err)
+		}
+
+		c.Data(200, "text/plain", b.Bytes())
+	})
+
+	return r
+}
+
+func TestDecompressGzip(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	gz := gzip.NewWriter(buf)
+	_, err := gz.Write([]byte(largeBody))
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+
+	req, _ := http.NewRequest("POST", "/echo", buf)
+	req.Header.Set("Content-Encoding", "gzip")
+
+	r := setupDecompressRouter()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, largeBody, w.Body.String())
+	assert.Equal(t, "", w.Header().Get("X-Request-Content-Encoding"))
+}
+
+func TestDecompressNoEncoding(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/echo", strings.NewReader(smallBody))
+
+	r := setupDecompressRouter()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, smallBody, w.Body.String())
+}
+
+func TestDecompressMaxSizeExceeded(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	gz := gzip.NewWriter(buf)
+	_, err := gz.Write([]byte(largeBody))
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+
+	req, _ := http.NewRequest("POST", "/echo", buf)
+	req.Header.Set("Content-Encoding", "gzip")
+
+	r := setupDecompressRouter(compress.WithMaxDecodedSize(10))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// The 413 is committed by decodedBody itself the instant the cap is
+	// crossed, before the handler's io.Copy panics on the resulting read
+	// error; gin's Recovery middleware then tries to write its own 500, but
+	// the status is already committed, so 413 is what the client sees.
+	assert.Equal(t, 413, w.Code)
+}
+
+func TestDecompressMaxSizeExactCapSucceeds(t *testing.T) {
+	exact := strings.Repeat("a", 10)
+
+	buf := bytes.NewBuffer(nil)
+	gz := gzip.NewWriter(buf)
+	_, err := gz.Write([]byte(exact))
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+
+	req, _ := http.NewRequest("POST", "/echo", buf)
+	req.Header.Set("Content-Encoding", "gzip")
+
+	r := setupDecompressRouter(compress.WithMaxDecodedSize(int64(len(exact))))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, exact, w.Body.String())
+}
+
+func TestDecompressMetricsHandler(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	gz := gzip.NewWriter(buf)
+	_, err := gz.Write([]byte(largeBody))
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+	compressedSize := buf.Len()
+
+	req, _ := http.NewRequest("POST", "/echo", buf)
+	req.Header.Set("Content-Encoding", "gzip")
+
+	mockHandler := &MockMetricsHandler{}
+	r := setupDecompressRouter(compress.WithDecompressMetricsHandler(mockHandler.Handle))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.True(t, mockHandler.Called)
+	assert.Equal(t, compressedSize, mockHandler.CalledWith.RequestOriginalSize)
+	assert.Equal(t, len(largeBody), mockHandler.CalledWith.RequestDecodedSize)
+}
+
+func TestCompressBypassContentTypeNotAllowed(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/large-image", nil)
+	req.Header.Add("Accept-Encoding", "gzip")
+	r := setupRouter()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	checkNoop(t, w)
+	assert.Equal(t, largeBody, w.Body.String())
+}
+
+func TestCompressBypassAlreadyEncoded(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/large-preencoded", nil)
+	req.Header.Add("Accept-Encoding", "gzip")
+	r := setupRouter()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// The handler's own Content-Encoding must be left untouched, and the body
+	// must not be compressed a second time.
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "", w.Header().Get("Vary"))
+	assert.Equal(t, largeBody, w.Body.String())
+}
+
+func TestCompressBypassGrpc(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/grpc", nil)
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Add("Accept-Encoding", "gzip")
+	r := setupRouter()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	checkNoop(t, w)
+	assert.Equal(t, largeBody, w.Body.String())
+}
+
+func TestCompressCustomContentTypes(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/large-image", nil)
+	req.Header.Add("Accept-Encoding", "gzip")
+	r := setupRouter(compress.WithCompressibleContentTypes([]string{"image/*"}))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	checkCompress(t, w, "gzip")
+}
+
+func TestCompressFlushModeAuto(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/stream", nil)
+	req.Header.Add("Accept-Encoding", "gzip")
+
+	mockHandler := &MockMetricsHandler{}
+	r := setupRouter(compress.WithMetricsHandler(mockHandler.Handle))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	checkNoop(t, w)
+	assert.Equal(t, "chunkchunkchunk", w.Body.String())
+
+	// Each pre-swap Flush must not double-count the bytes it flushes through:
+	// OriginalSize/CompressedSize should match the body exactly, not inflate
+	// with every Flush call.
+	assert.True(t, mockHandler.Called, "Metrics handler should have been called")
+	assert.Equal(t, len("chunkchunkchunk"), mockHandler.CalledWith.OriginalSize, "Original size should match the unbuffered body length")
+	assert.Equal(t, mockHandler.CalledWith.OriginalSize, mockHandler.CalledWith.CompressedSize, "Compressed size should equal original size when not compressed")
+}
+
+func TestCompressFlushModeAlways(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/stream", nil)
+	req.Header.Add("Accept-Encoding", "gzip")
+	r := setupRouter(compress.WithFlushMode(compress.FlushModeAlways))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	checkCompress(t, w, "gzip")
+
+	gz, err := gzip.NewReader(w.Body)
+	assert.NoError(t, err)
+	defer gz.Close()
+
+	b := bytes.NewBuffer(nil)
+	_, err = gz.WriteTo(b)
+	assert.NoError(t, err)
+	assert.Equal(t, "chunkchunkchunk", b.String())
+}
+
+func TestCompressMinContentLength(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/declared-small", nil)
+	req.Header.Add("Accept-Encoding", "gzip")
+	r := setupRouter(compress.WithMinContentLength(len(largeBody) + 1))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	checkNoop(t, w)
+	assert.Equal(t, largeBody, w.Body.String())
+}
+
 // Test for metrics handler when compressing
 func TestMetricsHandlerWithCompression(t *testing.T) {
 	req, _ := http.NewRequest("GET", "/large", nil)
@@ -333,3 +718,140 @@ func TestMetricsHandlerWithDifferentAlgorithms(t *testing.T) {
 		})
 	}
 }
+
+// identityAlgorithm is a minimal non-pooling compress.Algorithm used to
+// exercise RegisterAlgorithm/WithAlgorithm: it writes its input through
+// unchanged, prefixed by a marker so tests can tell it was used.
+type identityAlgorithm struct {
+	name string
+}
+
+func (a identityAlgorithm) Encoding() string { return a.name }
+
+func (a identityAlgorithm) NewWriter(w io.Writer) io.WriteCloser {
+	return identityWriteCloser{w}
+}
+
+func (a identityAlgorithm) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(r), nil
+}
+
+type identityWriteCloser struct {
+	w io.Writer
+}
+
+func (i identityWriteCloser) Write(b []byte) (int, error) { return i.w.Write(b) }
+func (i identityWriteCloser) Close() error                { return nil }
+
+func TestCompressRegisterAlgorithm(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/large", nil)
+	req.Header.Add("Accept-Encoding", "identity-test")
+
+	r := setupRouter(compress.RegisterAlgorithm(identityAlgorithm{name: "identity-test"}, 10))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	checkCompress(t, w, "identity-test")
+	assert.Equal(t, largeBody, w.Body.String())
+}
+
+func TestCompressWithAlgorithm(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/large", nil)
+	req.Header.Add("Accept-Encoding", "gzip")
+
+	r := setupRouter(compress.WithAlgorithm("gzip", identityAlgorithm{name: "gzip"}))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	checkCompress(t, w, "gzip")
+	assert.Equal(t, largeBody, w.Body.String())
+}
+
+// TestCompressZstdDictionary verifies that WithZstdDictionary is wired
+// through to the zstd encoder. An improperly-framed dictionary (as opposed
+// to one produced by "zstd --train") is rejected at option-construction
+// time, so the response still decodes as plain zstd.
+func TestCompressZstdDictionary(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/large", nil)
+	req.Header.Add("Accept-Encoding", "zstd")
+
+	r := setupRouter(compress.WithZstdDictionary([]byte("not a real dictionary")))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	checkCompress(t, w, "zstd")
+
+	zr, err := zstd.NewReader(w.Body)
+	assert.NoError(t, err)
+	defer zr.Close()
+
+	b, err := io.ReadAll(zr)
+	assert.NoError(t, err)
+	assert.Equal(t, largeBody, string(b))
+}
+
+// TestDecompressZstdDictionary is the Decompress-side counterpart of
+// TestCompressZstdDictionary: an improperly-framed dictionary must not
+// break decoding of plain zstd request bodies.
+func TestDecompressZstdDictionary(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	zw, err := zstd.NewWriter(buf)
+	assert.NoError(t, err)
+	_, err = zw.Write([]byte(largeBody))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+
+	req, _ := http.NewRequest("POST", "/echo", buf)
+	req.Header.Add("Content-Encoding", "zstd")
+
+	r := setupDecompressRouter(compress.WithDecompressZstdDictionary([]byte("not a real dictionary")))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, largeBody, w.Body.String())
+}
+
+// BenchmarkCompressLarge compares throughput serving /large with the
+// default pooled gzip algorithm against a drop-in replacement that
+// allocates a fresh gzip.Writer on every response, to quantify the benefit
+// of the sync.Pool-backed reuse in gzipAlgorithm.
+func BenchmarkCompressLarge(b *testing.B) {
+	run := func(b *testing.B, r *gin.Engine) {
+		req, _ := http.NewRequest("GET", "/large", nil)
+		req.Header.Add("Accept-Encoding", "gzip")
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+		}
+	}
+
+	b.Run("Pooled", func(b *testing.B) {
+		run(b, setupRouter())
+	})
+
+	b.Run("NonPooled", func(b *testing.B) {
+		run(b, setupRouter(compress.WithAlgorithm("gzip", nonPooledGzipAlgorithm{})))
+	})
+}
+
+// nonPooledGzipAlgorithm allocates a fresh gzip.Writer per response, used
+// only to benchmark against the default pooled implementation.
+type nonPooledGzipAlgorithm struct{}
+
+func (nonPooledGzipAlgorithm) Encoding() string { return "gzip" }
+
+func (nonPooledGzipAlgorithm) NewWriter(w io.Writer) io.WriteCloser {
+	zw, _ := gzip.NewWriterLevel(w, gzip.DefaultCompression)
+	return zw
+}
+
+func (nonPooledGzipAlgorithm) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
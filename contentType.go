@@ -0,0 +1,53 @@
+package compress
+
+/*
+gin-compress Copyright (C) 2022 Aurora McGinnis
+Modifications Copyright (C) 2025 Rubén del Campo
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+
+import "strings"
+
+// defaultCompressibleContentTypes is the allowlist used when
+// WithCompressibleContentTypes is not provided. It covers the textual
+// response types that typically benefit from compression while leaving
+// already-compressed media (images, video, archives) alone.
+func defaultCompressibleContentTypes() []string {
+	return []string{
+		"text/*",
+		"application/json",
+		"application/javascript",
+		"image/svg+xml",
+		"application/xml",
+	}
+}
+
+// contentTypeAllowed reports whether ct (a full Content-Type header value,
+// which may carry parameters such as "; charset=utf-8") matches one of
+// patterns. A pattern is either an exact MIME type ("application/json") or
+// a type wildcard ending in "/*" ("text/*"). A handler that hasn't set a
+// Content-Type yet is assumed compressible, preserving prior behavior.
+func contentTypeAllowed(patterns []string, ct string) bool {
+	mime, _, _ := strings.Cut(ct, ";")
+	mime = strings.TrimSpace(mime)
+	if mime == "" {
+		return true
+	}
+
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "/*"); ok {
+			if strings.HasPrefix(strings.ToLower(mime), strings.ToLower(prefix)+"/") {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(p, mime) {
+			return true
+		}
+	}
+
+	return false
+}
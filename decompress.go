@@ -0,0 +1,199 @@
+package compress
+
+/*
+gin-compress Copyright (C) 2022 Aurora McGinnis
+Modifications Copyright (C) 2025 Rubén del Campo
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultMaxDecodedSize is the cap on decompressed request body size used
+// when WithMaxDecodedSize is not provided. It exists to bound the amount of
+// memory/CPU a single compressed request can force the server to spend
+// decoding, i.e. a zip bomb.
+const defaultMaxDecodedSize = 10 << 20 // 10MiB
+
+// errDecodedSizeExceeded is returned by the wrapped request body reader once
+// more than the configured max decoded size has been read out of it.
+var errDecodedSizeExceeded = errors.New("compress: request body exceeds max decoded size")
+
+// DecompressOption configures the Decompress middleware.
+type DecompressOption func(*decompressOptions)
+
+type decompressOptions struct {
+	maxDecodedSize int64
+	metricsHandler MetricsHandler
+	algorithms     map[string]Algorithm
+}
+
+func newDecompressOptions() *decompressOptions {
+	return &decompressOptions{
+		maxDecodedSize: defaultMaxDecodedSize,
+		metricsHandler: noopMetricsHandler,
+		algorithms:     defaultAlgorithms(),
+	}
+}
+
+// WithMaxDecodedSize caps the number of bytes Decompress will read out of a
+// compressed request body. The moment a read would cross the cap, the
+// response is committed as 413 Request Entity Too Large before the error is
+// returned to the handler, so the status doesn't depend on the handler
+// noticing the read error and responding accordingly. This guards against
+// zip-bomb payloads that expand to many times their wire size.
+func WithMaxDecodedSize(n int64) DecompressOption {
+	return func(do *decompressOptions) {
+		do.maxDecodedSize = n
+	}
+}
+
+// WithDecompressMetricsHandler registers a handler invoked once the request
+// body has been fully read, with RequestOriginalSize/RequestDecodedSize
+// populated on the reported MetricsData.
+func WithDecompressMetricsHandler(h MetricsHandler) DecompressOption {
+	return func(do *decompressOptions) {
+		if h != nil {
+			do.metricsHandler = h
+		}
+	}
+}
+
+// WithDecompressZstdDictionary configures Decompress to decode zstd request
+// bodies using dict, mirroring WithZstdDictionary on the response-compression
+// side. It is a no-op if zstd isn't registered under its default name.
+func WithDecompressZstdDictionary(dict []byte) DecompressOption {
+	return func(do *decompressOptions) {
+		if z, ok := do.algorithms["zstd"].(zstdAlgorithm); ok {
+			do.algorithms["zstd"] = z.WithDict(dict)
+		}
+	}
+}
+
+// Decompress creates a middleware that transparently decodes a compressed
+// request body. It inspects the request's Content-Encoding header and, for
+// gzip, deflate, br or zstd, replaces c.Request.Body with a reader that
+// decodes on the fly, stripping Content-Encoding and Content-Length since
+// neither describes the decoded body handlers will read.
+func Decompress(opts ...DecompressOption) gin.HandlerFunc {
+	do := newDecompressOptions()
+	for _, opt := range opts {
+		opt(do)
+	}
+
+	algos := do.algorithms
+
+	return func(c *gin.Context) {
+		encoding := c.GetHeader("Content-Encoding")
+		if encoding == "" || encoding == "identity" {
+			c.Next()
+			return
+		}
+
+		algo, ok := algos[encoding]
+		if !ok {
+			c.Next()
+			return
+		}
+
+		decoder, err := algo.NewReader(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+
+		body := &decodedBody{
+			decoder:        decoder,
+			original:       c.Request.Body,
+			remaining:      do.maxDecodedSize,
+			originalSize:   int(c.Request.ContentLength),
+			metricsHandler: do.metricsHandler,
+			onExceeded:     func() { c.AbortWithStatus(http.StatusRequestEntityTooLarge) },
+		}
+
+		c.Request.Body = body
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Del("Content-Length")
+		c.Request.ContentLength = -1
+
+		defer body.Close()
+
+		c.Next()
+	}
+}
+
+// decodedBody wraps a decompressing reader around the original request
+// body, enforcing a max decoded size and reporting the resulting sizes to a
+// MetricsHandler on Close.
+type decodedBody struct {
+	decoder      io.ReadCloser
+	original     io.ReadCloser
+	remaining    int64
+	originalSize int
+	decodedSize  int
+
+	// onExceeded commits the 413 response the instant the cap is crossed,
+	// regardless of whether the handler's own error handling would have
+	// surfaced one.
+	onExceeded func()
+
+	metricsHandler MetricsHandler
+	closed         bool
+	exceeded       bool
+}
+
+// Read enforces remaining as a strict cap: a body that decodes to exactly
+// remaining bytes is a legitimate, complete body (io.EOF, not an error), so
+// reaching remaining == 0 only reports errDecodedSizeExceeded once a further
+// read shows there's more data past the cap.
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		var probe [1]byte
+		n, err := b.decoder.Read(probe[:])
+		if n > 0 {
+			b.exceeded = true
+			b.onExceeded()
+			return 0, errDecodedSizeExceeded
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+
+	n, err := b.decoder.Read(p)
+	b.remaining -= int64(n)
+	b.decodedSize += n
+	return n, err
+}
+
+func (b *decodedBody) Close() error {
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+
+	b.metricsHandler(MetricsData{
+		RequestOriginalSize: b.originalSize,
+		RequestDecodedSize:  b.decodedSize,
+	})
+
+	decodeErr := b.decoder.Close()
+	originalErr := b.original.Close()
+	if decodeErr != nil {
+		return decodeErr
+	}
+	return originalErr
+}
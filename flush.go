@@ -0,0 +1,52 @@
+package compress
+
+/*
+gin-compress Copyright (C) 2022 Aurora McGinnis
+Modifications Copyright (C) 2025 Rubén del Campo
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+
+// FlushMode controls how respWriter.Flush behaves when called before the
+// response has been swapped to its compressor, which happens whenever a
+// handler flushes before threshold bytes have been buffered (SSE, chunked
+// JSON streams, long-poll).
+type FlushMode int
+
+const (
+	// FlushModeAuto bypasses compression for the rest of the response the
+	// first time Flush is called before the threshold is reached, so the
+	// handler's explicit flushes are honored immediately rather than sitting
+	// in rw.buf. This is the default: it favors not breaking streaming
+	// responses over compressing them.
+	FlushModeAuto FlushMode = iota
+	// FlushModeAlways forces an immediate swap to the compressor on the
+	// first Flush, regardless of how few bytes have been buffered, so
+	// streamed responses are still compressed.
+	FlushModeAlways
+	// FlushModeNever ignores Flush calls made before the swap; the response
+	// keeps buffering toward threshold exactly as if Flush had not been
+	// called.
+	FlushModeNever
+)
+
+// WithFlushMode selects how the middleware reacts to a handler calling
+// Flush() on the response writer before threshold bytes have been written.
+func WithFlushMode(mode FlushMode) CompressOption {
+	return func(co *compressOptions) {
+		co.flushMode = mode
+	}
+}
+
+// WithMinContentLength sets a minimum Content-Length for compression to be
+// attempted at all. If a handler sets Content-Length before writing and its
+// value is below this threshold, Write skips buffering entirely and copies
+// bytes straight through uncompressed, since the body is already known to be
+// too small to be worth compressing.
+func WithMinContentLength(n int) CompressOption {
+	return func(co *compressOptions) {
+		co.minContentLength = n
+	}
+}
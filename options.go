@@ -0,0 +1,189 @@
+package compress
+
+/*
+gin-compress Copyright (C) 2022 Aurora McGinnis
+Modifications Copyright (C) 2025 Rubén del Campo
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+
+import "regexp"
+
+// Compression level aliases re-exported so callers don't need to import
+// flate/gzip/zlib directly just to call WithCompressLevel.
+const (
+	GzFlateDefaultCompression = -1
+	GzFlateNoCompression      = 0
+	GzFlateBestSpeed          = 1
+	GzFlateBestCompression    = 9
+)
+
+// defaultThreshold is the number of bytes that must be buffered before the
+// middleware decides the response is worth compressing.
+const defaultThreshold = 1024
+
+// MetricsData carries accounting information about a single response,
+// reported to a MetricsHandler once the response has been fully written.
+type MetricsData struct {
+	// OriginalSize is the number of bytes the handler wrote to the response.
+	OriginalSize int
+	// CompressedSize is the number of bytes actually sent over the wire.
+	// Equal to OriginalSize when CompressionApplied is false.
+	CompressedSize int
+	// CompressionApplied reports whether the response was compressed.
+	CompressionApplied bool
+	// EncodingUsed is the Content-Encoding token used, or "" if none.
+	EncodingUsed string
+
+	// RequestOriginalSize is the number of bytes read off the wire for the
+	// request body, before decompression. Only populated by Decompress.
+	RequestOriginalSize int
+	// RequestDecodedSize is the number of bytes the request body expanded to
+	// after decompression. Only populated by Decompress.
+	RequestDecodedSize int
+}
+
+// MetricsHandler is called once per request with accounting data about the
+// response that was just written.
+type MetricsHandler func(MetricsData)
+
+// noopMetricsHandler is the default MetricsHandler; it does nothing.
+func noopMetricsHandler(MetricsData) {}
+
+// compressOptions holds the resolved configuration built up from the
+// CompressOption values passed to Compress().
+type compressOptions struct {
+	threshold        int
+	levels           map[string]int
+	algorithms       map[string]Algorithm
+	priorities       map[string]float64
+	metricsHandler   MetricsHandler
+	contentTypes     []string
+	flushMode        FlushMode
+	minContentLength int
+	defaultEncoding  string
+	includePaths     []*regexp.Regexp
+	excludePaths     []*regexp.Regexp
+	excludeHosts     []*regexp.Regexp
+}
+
+// newCompressOptions returns a compressOptions populated with this package's
+// defaults: gzip, brotli, zstd and deflate registered at their default
+// compression levels, a 1KiB threshold, the default compressible
+// Content-Type allowlist, and a no-op metrics handler.
+func newCompressOptions() *compressOptions {
+	co := &compressOptions{
+		threshold:      defaultThreshold,
+		levels:         map[string]int{},
+		algorithms:     map[string]Algorithm{},
+		priorities:     map[string]float64{},
+		metricsHandler: noopMetricsHandler,
+		contentTypes:   defaultCompressibleContentTypes(),
+	}
+
+	for name, a := range defaultAlgorithms() {
+		co.algorithms[name] = a
+	}
+	for name, p := range defaultPriorities() {
+		co.priorities[name] = p
+	}
+
+	return co
+}
+
+// CompressOption configures the Compress middleware.
+type CompressOption func(*compressOptions)
+
+// WithCompressLevel sets the compression level used for the named encoding
+// (one of "gzip", "br", "zstd", "deflate"). The accepted values for level
+// depend on the underlying codec; for gzip/deflate these mirror the
+// compress/flate constants (GzFlateBestSpeed..GzFlateBestCompression), while
+// for brotli and zstd they follow their own native ranges. It has no effect
+// on algorithms that don't implement LeveledAlgorithm.
+func WithCompressLevel(name string, level int) CompressOption {
+	return func(co *compressOptions) {
+		co.levels[name] = level
+		if a, ok := co.algorithms[name].(LeveledAlgorithm); ok {
+			co.algorithms[name] = a.WithLevel(level)
+		}
+	}
+}
+
+// RegisterAlgorithm adds a to the set of encodings Compress can negotiate,
+// under the Content-Encoding token a.Encoding() reports, weighted by
+// priority when breaking ties between equally-weighted Accept-Encoding
+// entries (see defaultPriorities for the scale the built-ins use). Use this
+// to plug in a codec Compress doesn't ship, such as Snappy or LZ4.
+func RegisterAlgorithm(a Algorithm, priority float64) CompressOption {
+	return func(co *compressOptions) {
+		co.algorithms[a.Encoding()] = a
+		co.priorities[a.Encoding()] = priority
+	}
+}
+
+// WithAlgorithm replaces the algorithm registered for an existing
+// Content-Encoding token (one of "gzip", "br", "zstd", "deflate", or any
+// name previously added via RegisterAlgorithm) without changing its
+// negotiation priority. Use this to swap in a differently-configured
+// implementation, e.g. a zstdAlgorithm built with custom encoder options.
+func WithAlgorithm(name string, a Algorithm) CompressOption {
+	return func(co *compressOptions) {
+		co.algorithms[name] = a
+	}
+}
+
+// WithZstdDictionary configures the zstd algorithm to use dict for both
+// compression and decompression, via zstd.WithEncoderDict/WithDecoderDicts.
+// It is a no-op if zstd isn't registered under its default name.
+func WithZstdDictionary(dict []byte) CompressOption {
+	return func(co *compressOptions) {
+		if z, ok := co.algorithms["zstd"].(zstdAlgorithm); ok {
+			co.algorithms["zstd"] = z.WithDict(dict)
+		}
+	}
+}
+
+// WithMetricsHandler registers a handler that is invoked once per request
+// with accounting information about the response that was written.
+func WithMetricsHandler(h MetricsHandler) CompressOption {
+	return func(co *compressOptions) {
+		if h != nil {
+			co.metricsHandler = h
+		}
+	}
+}
+
+// WithThreshold sets the minimum number of bytes that must be buffered
+// before the middleware swaps in a compressor for the response.
+func WithThreshold(threshold int) CompressOption {
+	return func(co *compressOptions) {
+		co.threshold = threshold
+	}
+}
+
+// WithCompressibleContentTypes overrides the allowlist of response
+// Content-Types eligible for compression. Entries may be an exact MIME
+// type ("application/json") or a type wildcard ("text/*"). Responses whose
+// Content-Type doesn't match any entry are written through uncompressed,
+// which avoids wasting CPU on already-compressed media such as images.
+func WithCompressibleContentTypes(types []string) CompressOption {
+	return func(co *compressOptions) {
+		co.contentTypes = types
+	}
+}
+
+// WithDefaultEncoding sets the algorithm to use when a request has no
+// Accept-Encoding header at all, instead of skipping compression. Per
+// RFC 9110 §12.5.3, a missing Accept-Encoding means any encoding (or none)
+// is acceptable to the client, so this is a Compress-specific choice rather
+// than a negotiation outcome; it does not apply when the header is present
+// but names nothing Compress supports. name must match an encoding
+// registered on this middleware (one of "gzip", "br", "zstd", "deflate", or
+// a name added via RegisterAlgorithm); unknown names are a no-op.
+func WithDefaultEncoding(name string) CompressOption {
+	return func(co *compressOptions) {
+		co.defaultEncoding = name
+	}
+}
@@ -0,0 +1,138 @@
+package compress
+
+/*
+gin-compress Copyright (C) 2022 Aurora McGinnis
+Modifications Copyright (C) 2025 Rubén del Campo
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+
+import (
+	"net"
+	"regexp"
+	"strings"
+)
+
+// WithIncludePaths restricts compression to requests whose URL path matches
+// at least one pattern, using glob syntax: "*" matches within a single path
+// segment (e.g. "/api/*" matches "/api/users" but not "/api/v1/users"),
+// "**" matches across segments (e.g. "/api/**" matches both), and "?"
+// matches a single non-"/" character. Patterns are compiled to regular
+// expressions once, here, rather than per request. When unset, every path
+// is eligible, subject to WithExcludePaths. Malformed patterns never match.
+func WithIncludePaths(patterns []string) CompressOption {
+	return func(co *compressOptions) {
+		co.includePaths = compileGlobs(patterns, false)
+	}
+}
+
+// WithExcludePaths skips compression for requests whose URL path matches
+// any pattern, using the same glob syntax as WithIncludePaths. Exclusion
+// takes precedence over WithIncludePaths: a path matching both is skipped.
+func WithExcludePaths(patterns []string) CompressOption {
+	return func(co *compressOptions) {
+		co.excludePaths = compileGlobs(patterns, false)
+	}
+}
+
+// WithExcludeHosts skips compression for requests whose Host header
+// (without port) matches any pattern, using the same glob syntax as
+// WithIncludePaths (e.g. "*.internal.example.com"). Hostnames are matched
+// case-insensitively, per RFC 1123.
+func WithExcludeHosts(patterns []string) CompressOption {
+	return func(co *compressOptions) {
+		co.excludeHosts = compileGlobs(patterns, true)
+	}
+}
+
+// compileGlobs translates each pattern into a compiled regular expression
+// via globToRegex, so matching at request time is a cheap MatchString
+// instead of repeating the translation on every request. A pattern that
+// fails to compile is dropped rather than propagated as an error, so it
+// simply never matches.
+func compileGlobs(patterns []string, foldCase bool) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		expr := globToRegex(p)
+		if foldCase {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			continue
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled
+}
+
+// globToRegex converts a glob pattern into an anchored regular expression:
+// "**" becomes ".*" and is allowed to cross "/" boundaries, a lone "*"
+// becomes "[^/]*" and stays within one path segment, "?" becomes "[^/]",
+// and every other character is matched literally.
+func globToRegex(pattern string) string {
+	var sb strings.Builder
+	sb.WriteByte('^')
+
+	for i := 0; i < len(pattern); {
+		switch pattern[i] {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				sb.WriteString(".*")
+				i += 2
+			} else {
+				sb.WriteString("[^/]*")
+				i++
+			}
+		case '?':
+			sb.WriteString("[^/]")
+			i++
+		default:
+			j := i
+			for j < len(pattern) && pattern[j] != '*' && pattern[j] != '?' {
+				j++
+			}
+			sb.WriteString(regexp.QuoteMeta(pattern[i:j]))
+			i = j
+		}
+	}
+
+	sb.WriteByte('$')
+	return sb.String()
+}
+
+// matchesAny reports whether name matches any of the compiled patterns.
+func matchesAny(patterns []*regexp.Regexp, name string) bool {
+	for _, re := range patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestExcluded reports whether r should skip compression entirely based
+// on the configured include/exclude path and host rules: an excluded host
+// or path always skips, and a non-empty include list requires a match.
+func requestExcluded(co *compressOptions, host, urlPath string) bool {
+	if len(co.excludeHosts) > 0 {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if matchesAny(co.excludeHosts, host) {
+			return true
+		}
+	}
+
+	if matchesAny(co.excludePaths, urlPath) {
+		return true
+	}
+
+	if len(co.includePaths) > 0 && !matchesAny(co.includePaths, urlPath) {
+		return true
+	}
+
+	return false
+}